@@ -1,13 +1,16 @@
 package lib
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -77,15 +80,90 @@ func getEnvInt(key string, fallback int) int {
 	return n
 }
 
+func getEnvFloat(key string, fallback float64) float64 {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return fallback
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f <= 0 {
+		return fallback
+	}
+
+	return f
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return fallback
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fallback
+	}
+
+	return b
+}
+
+// retryDelay implements the AWS-style "full jitter" backoff: the sleep is a
+// random duration between 0 and the exponentially growing ceiling, which
+// avoids many parallel callers waking up in lockstep after a shared failure.
 func retryDelay(cfg httpRequestConfig, attempt int) time.Duration {
-	delay := cfg.backoffBase
+	ceiling := cfg.backoffBase
 	for i := 1; i < attempt; i++ {
-		delay *= 2
-		if delay >= cfg.backoffLimit {
-			return cfg.backoffLimit
+		ceiling *= 2
+		if ceiling >= cfg.backoffLimit {
+			ceiling = cfg.backoffLimit
+			break
 		}
 	}
-	return delay
+
+	if ceiling <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// parseRetryAfter parses a Retry-After header value, which may be either
+// delta-seconds or an HTTP-date, capping the result at limit. It reports
+// false for malformed or missing values so callers fall back to the
+// computed backoff.
+func parseRetryAfter(header string, limit time.Duration) (time.Duration, bool) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+
+		d := time.Duration(seconds) * time.Second
+		if d > limit {
+			d = limit
+		}
+
+		return d, true
+	}
+
+	if t, err := http.ParseTime(header); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		if d > limit {
+			d = limit
+		}
+
+		return d, true
+	}
+
+	return 0, false
 }
 
 func isRetryableStatusCode(code int) bool {
@@ -104,35 +182,128 @@ func isRetryableStatusCode(code int) bool {
 }
 
 func getRemoteURLResponse(url string) (*http.Response, error) {
+	return getRemoteURLResponseContext(context.Background(), url)
+}
+
+func getRemoteURLResponseContext(ctx context.Context, url string) (*http.Response, error) {
 	cfg := getHTTPRequestConfig()
-	client := &http.Client{
-		Timeout: cfg.timeout,
+	client, err := getHTTPClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build HTTP client: %w", err)
+	}
+
+	cacheEntry, cachedBody, cached := (*httpCacheEntry)(nil), []byte(nil), false
+	if httpCacheEnabled() {
+		cacheEntry, cachedBody, cached = loadHTTPCacheEntry(url)
+	}
+
+	if cached && withinHTTPCacheFreshness(cacheEntry) {
+		atomic.AddInt64(&httpCacheHits, 1)
+		return newCachedHTTPResponse(cacheEntry, cachedBody), nil
 	}
 
 	var lastErr error
+	var retryAfter time.Duration
+	var haveRetryAfter bool
+	var nonRetryableStatus bool
 
 	for attempt := 1; attempt <= cfg.maxRetries; attempt++ {
-		resp, err := client.Get(url)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		haveRetryAfter = false
+
+		// Each attempt gets its own bounded context rather than relying on
+		// client.Timeout, since the client's *http.Client is shared across
+		// concurrent fetches and mutating its Timeout field after
+		// construction would race with other goroutines' in-flight requests.
+		reqCtx, cancel := context.WithTimeout(ctx, cfg.timeout)
+
+		req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, url, nil)
 		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to build request -> %s: %w", url, err)
+		}
+
+		req.Header.Set("User-Agent", httpUserAgent())
+
+		if cached {
+			if cacheEntry.ETag != "" {
+				req.Header.Set("If-None-Match", cacheEntry.ETag)
+			}
+			if cacheEntry.LastModified != "" {
+				req.Header.Set("If-Modified-Since", cacheEntry.LastModified)
+			}
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			cancel()
 			lastErr = err
-		} else {
-			if resp.StatusCode == http.StatusOK {
-				return resp, nil
+		} else if resp.StatusCode == http.StatusNotModified {
+			resp.Body.Close()
+			cancel()
+
+			if cached {
+				atomic.AddInt64(&httpCacheHits, 1)
+				return newCachedHTTPResponse(cacheEntry, cachedBody), nil
 			}
 
+			lastErr = fmt.Errorf("received 304 Not Modified without a cached entry")
+		} else if resp.StatusCode == http.StatusOK {
+			body, readErr := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			cancel()
+
+			if readErr != nil {
+				lastErr = readErr
+			} else {
+				if httpCacheEnabled() {
+					atomic.AddInt64(&httpCacheMisses, 1)
+					storeHTTPCacheEntry(url, newHTTPCacheEntry(resp), body)
+				}
+
+				return newBufferedHTTPResponse(resp, body), nil
+			}
+		} else {
 			lastErr = fmt.Errorf("%s", resp.Status)
+
+			if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), cfg.backoffLimit); ok {
+				retryAfter, haveRetryAfter = d, true
+			}
+
 			resp.Body.Close()
+			cancel()
 
 			if !isRetryableStatusCode(resp.StatusCode) {
+				nonRetryableStatus = true
 				break
 			}
 		}
 
 		if attempt < cfg.maxRetries {
-			time.Sleep(retryDelay(cfg, attempt))
+			sleep := retryDelay(cfg, attempt)
+			if haveRetryAfter {
+				sleep = retryAfter
+			}
+
+			if sleepErr := sleepContext(ctx, sleep); sleepErr != nil {
+				return nil, sleepErr
+			}
 		}
 	}
 
+	// Only a network failure (or an exhausted retry budget against a
+	// retryable status) should fall back to a stale cache entry. A
+	// non-retryable response like 401/403/404 is a definitive answer from
+	// the server and must be surfaced as an error rather than papered over
+	// with stale data.
+	if cached && !nonRetryableStatus && withinHTTPCacheStaleGrace(cacheEntry) {
+		atomic.AddInt64(&httpCacheStale, 1)
+		return newCachedHTTPResponse(cacheEntry, cachedBody), nil
+	}
+
 	if lastErr != nil {
 		return nil, fmt.Errorf("failed to get remote content -> %s: %w", url, lastErr)
 	}
@@ -140,8 +311,32 @@ func getRemoteURLResponse(url string) (*http.Response, error) {
 	return nil, fmt.Errorf("failed to get remote content -> %s", url)
 }
 
+// sleepContext sleeps for d, returning early with ctx.Err() if ctx is
+// cancelled first so a retry loop never blocks past a caller's deadline.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
 func GetRemoteURLContent(url string) ([]byte, error) {
-	resp, err := getRemoteURLResponse(url)
+	return GetRemoteURLContentContext(context.Background(), url)
+}
+
+func GetRemoteURLReader(url string) (io.ReadCloser, error) {
+	return GetRemoteURLReaderContext(context.Background(), url)
+}
+
+// GetRemoteURLContentContext behaves like GetRemoteURLContent but aborts the
+// fetch (including any pending retry) as soon as ctx is cancelled.
+func GetRemoteURLContentContext(ctx context.Context, url string) ([]byte, error) {
+	resp, err := getRemoteURLResponseContext(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -150,8 +345,10 @@ func GetRemoteURLContent(url string) ([]byte, error) {
 	return io.ReadAll(resp.Body)
 }
 
-func GetRemoteURLReader(url string) (io.ReadCloser, error) {
-	resp, err := getRemoteURLResponse(url)
+// GetRemoteURLReaderContext behaves like GetRemoteURLReader but aborts the
+// fetch (including any pending retry) as soon as ctx is cancelled.
+func GetRemoteURLReaderContext(ctx context.Context, url string) (io.ReadCloser, error) {
+	resp, err := getRemoteURLResponseContext(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,143 @@
+package lib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetryDelayFullJitterDistribution(t *testing.T) {
+	cfg := httpRequestConfig{
+		backoffBase:  1 * time.Second,
+		backoffLimit: 8 * time.Second,
+	}
+
+	ceilings := map[int]time.Duration{
+		1: 1 * time.Second,
+		2: 2 * time.Second,
+		3: 4 * time.Second,
+		4: 8 * time.Second,
+		5: 8 * time.Second, // capped at backoffLimit
+	}
+
+	for attempt, ceiling := range ceilings {
+		minDelay := ceiling
+		for i := 0; i < 50; i++ {
+			d := retryDelay(cfg, attempt)
+			if d < 0 || d > ceiling {
+				t.Fatalf("attempt %d: retryDelay returned %v, want within [0, %v]", attempt, d, ceiling)
+			}
+			if d < minDelay {
+				minDelay = d
+			}
+		}
+		// With full jitter, 50 draws all landing in the upper half of
+		// [0, ceiling] has probability 2^-50.
+		if minDelay >= ceiling/2 {
+			t.Fatalf("attempt %d: all 50 delays were >= %v, want jitter across [0, %v]", attempt, ceiling/2, ceiling)
+		}
+	}
+}
+
+func TestRetryDelayZeroBackoffBase(t *testing.T) {
+	cfg := httpRequestConfig{backoffBase: 0, backoffLimit: 0}
+
+	if d := retryDelay(cfg, 1); d != 0 {
+		t.Fatalf("retryDelay with zero backoff = %v, want 0", d)
+	}
+}
+
+func TestParseRetryAfterDeltaSeconds(t *testing.T) {
+	d, ok := parseRetryAfter("5", 10*time.Second)
+	if !ok {
+		t.Fatal("expected ok=true for delta-seconds value")
+	}
+	if d != 5*time.Second {
+		t.Fatalf("got %v, want 5s", d)
+	}
+}
+
+func TestParseRetryAfterDeltaSecondsCappedAtLimit(t *testing.T) {
+	d, ok := parseRetryAfter("30", 10*time.Second)
+	if !ok {
+		t.Fatal("expected ok=true")
+	}
+	if d != 10*time.Second {
+		t.Fatalf("got %v, want capped 10s", d)
+	}
+}
+
+func TestParseRetryAfterHTTPDate(t *testing.T) {
+	future := time.Now().Add(5 * time.Second)
+	d, ok := parseRetryAfter(future.UTC().Format(http.TimeFormat), 1*time.Hour)
+	if !ok {
+		t.Fatal("expected ok=true for HTTP-date value")
+	}
+	if d <= 0 || d > 6*time.Second {
+		t.Fatalf("got %v, want roughly 5s", d)
+	}
+}
+
+func TestParseRetryAfterMalformedValues(t *testing.T) {
+	cases := []string{"", "not-a-number", "-5", "not a date at all"}
+
+	for _, header := range cases {
+		if d, ok := parseRetryAfter(header, 10*time.Second); ok {
+			t.Errorf("parseRetryAfter(%q) = (%v, true), want ok=false", header, d)
+		}
+	}
+}
+
+func TestGetRemoteURLResponseContextHonorsRetryAfter(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	t.Setenv("GEOIP_HTTP_MAX_RETRIES", "5")
+	t.Setenv("GEOIP_HTTP_BACKOFF_BASE", "10")
+
+	start := time.Now()
+	body, err := GetRemoteURLContent(server.URL)
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("GetRemoteURLContent failed: %v", err)
+	}
+	if string(body) != "ok" {
+		t.Fatalf("got body %q, want %q", body, "ok")
+	}
+	if attempts != 3 {
+		t.Fatalf("got %d attempts, want 3", attempts)
+	}
+	// Retry-After: 0 replaces the up-to-10s jittered backoff between attempts.
+	if elapsed > 2*time.Second {
+		t.Fatalf("retries took %v, want Retry-After: 0 to skip the backoff wait", elapsed)
+	}
+}
+
+func TestGetRemoteURLResponseContextStopsOnNonRetryableStatus(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	t.Setenv("GEOIP_HTTP_MAX_RETRIES", "5")
+
+	if _, err := GetRemoteURLContent(server.URL); err == nil {
+		t.Fatal("expected an error for a 404 response")
+	}
+	if attempts != 1 {
+		t.Fatalf("got %d attempts, want 1 (no retries for a non-retryable status)", attempts)
+	}
+}
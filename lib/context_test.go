@@ -0,0 +1,75 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetRemoteURLContentContextCancelledDuringRetryWait(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	t.Setenv("GEOIP_HTTP_MAX_RETRIES", "5")
+	t.Setenv("GEOIP_HTTP_BACKOFF_BASE", "30")
+	t.Setenv("GEOIP_HTTP_BACKOFF_LIMIT", "30")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	_, err := GetRemoteURLContentContext(ctx, server.URL)
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got err %v, want context.Canceled", err)
+	}
+	if elapsed > 5*time.Second {
+		t.Fatalf("cancellation took %v, want it to abort the pending retry wait quickly", elapsed)
+	}
+}
+
+func TestGetRemoteURLContentContextAlreadyCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := GetRemoteURLContentContext(ctx, server.URL); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got err %v, want context.Canceled", err)
+	}
+}
+
+func TestRemoteFetcherFetchAllContextAbortsOnCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	fetcher := NewRemoteFetcher()
+	results := fetcher.FetchAllContext(ctx, []string{server.URL, server.URL, server.URL})
+
+	for i, result := range results {
+		if result.Err == nil {
+			t.Errorf("result %d: expected an error from the cancelled context", i)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultHTTPMaxConcurrency = 8
+	defaultHTTPPerHostRPS     = 4.0
+	defaultHTTPPerHostBurst   = 4
+)
+
+// FetchResult is the outcome of fetching a single URL through a RemoteFetcher.
+type FetchResult struct {
+	URL  string
+	Data []byte
+	Err  error
+}
+
+// RemoteFetcher batches many URL fetches and executes them concurrently with
+// a bounded worker pool, while capping the request rate to each individual
+// host so a single mirror isn't hammered by the whole batch at once.
+type RemoteFetcher struct {
+	maxConcurrency int
+	perHostRPS     float64
+	perHostBurst   int
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+// NewRemoteFetcher builds a RemoteFetcher configured from
+// GEOIP_HTTP_MAX_CONCURRENCY, GEOIP_HTTP_PER_HOST_RPS and
+// GEOIP_HTTP_PER_HOST_BURST.
+func NewRemoteFetcher() *RemoteFetcher {
+	return &RemoteFetcher{
+		maxConcurrency: getEnvInt("GEOIP_HTTP_MAX_CONCURRENCY", defaultHTTPMaxConcurrency),
+		perHostRPS:     getEnvFloat("GEOIP_HTTP_PER_HOST_RPS", defaultHTTPPerHostRPS),
+		perHostBurst:   getEnvInt("GEOIP_HTTP_PER_HOST_BURST", defaultHTTPPerHostBurst),
+		limiters:       make(map[string]*rate.Limiter),
+	}
+}
+
+func (f *RemoteFetcher) limiterFor(host string) *rate.Limiter {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	limiter, ok := f.limiters[host]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Limit(f.perHostRPS), f.perHostBurst)
+		f.limiters[host] = limiter
+	}
+
+	return limiter
+}
+
+// FetchAll fetches every URL concurrently, bounded by maxConcurrency and the
+// per-host rate limiters, and returns results in the same order as urls.
+func (f *RemoteFetcher) FetchAll(urls []string) []FetchResult {
+	return f.FetchAllContext(context.Background(), urls)
+}
+
+// FetchAllContext behaves like FetchAll but aborts any in-flight or pending
+// fetch as soon as ctx is cancelled.
+func (f *RemoteFetcher) FetchAllContext(ctx context.Context, urls []string) []FetchResult {
+	results := make([]FetchResult, len(urls))
+	sem := make(chan struct{}, f.maxConcurrency)
+
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int, u string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			results[i] = f.fetchOne(ctx, u)
+		}(i, u)
+	}
+	wg.Wait()
+
+	return results
+}
+
+func (f *RemoteFetcher) fetchOne(ctx context.Context, rawURL string) FetchResult {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return FetchResult{URL: rawURL, Err: fmt.Errorf("invalid URL -> %s: %w", rawURL, err)}
+	}
+
+	if err := f.limiterFor(parsed.Host).Wait(ctx); err != nil {
+		return FetchResult{URL: rawURL, Err: err}
+	}
+
+	data, err := GetRemoteURLContentContext(ctx, rawURL)
+	return FetchResult{URL: rawURL, Data: data, Err: err}
+}
@@ -0,0 +1,98 @@
+package lib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRemoteFetcherFetchAllPreservesOrderAndResults(t *testing.T) {
+	serverA := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("a"))
+	}))
+	defer serverA.Close()
+
+	serverB := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("b"))
+	}))
+	defer serverB.Close()
+
+	urls := []string{serverA.URL, serverB.URL, serverA.URL}
+
+	fetcher := NewRemoteFetcher()
+	results := fetcher.FetchAll(urls)
+
+	if len(results) != len(urls) {
+		t.Fatalf("got %d results, want %d", len(results), len(urls))
+	}
+
+	want := []string{"a", "b", "a"}
+	for i, result := range results {
+		if result.Err != nil {
+			t.Fatalf("result %d: unexpected error: %v", i, result.Err)
+		}
+		if result.URL != urls[i] {
+			t.Errorf("result %d: URL = %q, want %q", i, result.URL, urls[i])
+		}
+		if string(result.Data) != want[i] {
+			t.Errorf("result %d: Data = %q, want %q", i, result.Data, want[i])
+		}
+	}
+}
+
+func TestRemoteFetcherFetchAllReportsPerURLErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	fetcher := NewRemoteFetcher()
+	results := fetcher.FetchAll([]string{server.URL, "://not-a-valid-url"})
+
+	if results[0].Err != nil {
+		t.Fatalf("expected first URL to succeed, got: %v", results[0].Err)
+	}
+	if results[1].Err == nil {
+		t.Fatal("expected an error for the malformed URL")
+	}
+}
+
+func TestRemoteFetcherLimitsPerHostRate(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	t.Setenv("GEOIP_HTTP_PER_HOST_RPS", "2")
+	t.Setenv("GEOIP_HTTP_PER_HOST_BURST", "2")
+	t.Setenv("GEOIP_HTTP_MAX_CONCURRENCY", "8")
+
+	fetcher := NewRemoteFetcher()
+
+	urls := make([]string, 6)
+	for i := range urls {
+		urls[i] = server.URL
+	}
+
+	start := time.Now()
+	results := fetcher.FetchAll(urls)
+	elapsed := time.Since(start)
+	for i, result := range results {
+		if result.Err != nil {
+			t.Fatalf("result %d: unexpected error: %v", i, result.Err)
+		}
+	}
+
+	if requests != len(urls) {
+		t.Fatalf("got %d requests served, want %d", requests, len(urls))
+	}
+
+	// A burst of 2 lets the first two through at once; the other four wait
+	// 0.5s apart at 2 RPS, so the batch cannot finish in under about 2s.
+	if elapsed < 1500*time.Millisecond {
+		t.Fatalf("6 fetches to one host took %v, want at least 1.5s at 2 RPS with burst 2", elapsed)
+	}
+}
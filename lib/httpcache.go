@@ -0,0 +1,183 @@
+package lib
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// defaultHTTPCacheStaleGrace is how long a cached entry may be served after
+// a failed fetch before it is considered too stale to fall back on.
+const defaultHTTPCacheStaleGrace = 24 * time.Hour
+
+var (
+	httpCacheHits   int64
+	httpCacheMisses int64
+	httpCacheStale  int64
+)
+
+// CacheStats reports cumulative hit/miss/stale counts for the on-disk HTTP
+// cache across the lifetime of the process.
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+	Stale  int64
+}
+
+// GetCacheStats returns a snapshot of the current HTTP cache counters.
+func GetCacheStats() CacheStats {
+	return CacheStats{
+		Hits:   atomic.LoadInt64(&httpCacheHits),
+		Misses: atomic.LoadInt64(&httpCacheMisses),
+		Stale:  atomic.LoadInt64(&httpCacheStale),
+	}
+}
+
+type httpCacheEntry struct {
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"last_modified,omitempty"`
+	MaxAge       int         `json:"max_age,omitempty"`
+	FetchedAt    time.Time   `json:"fetched_at"`
+	Header       http.Header `json:"header"`
+}
+
+func httpCacheDir() string {
+	return strings.TrimSpace(os.Getenv("GEOIP_HTTP_CACHE_DIR"))
+}
+
+func httpCacheEnabled() bool {
+	if getEnvBool("GEOIP_NO_HTTP_CACHE", false) {
+		return false
+	}
+
+	return httpCacheDir() != ""
+}
+
+func httpCacheKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+func httpCachePaths(dir, url string) (metaPath, bodyPath string) {
+	key := httpCacheKey(url)
+	sub := filepath.Join(dir, key[:2])
+	return filepath.Join(sub, key+".json"), filepath.Join(sub, key+".body")
+}
+
+func loadHTTPCacheEntry(url string) (entry *httpCacheEntry, body []byte, ok bool) {
+	dir := httpCacheDir()
+	if dir == "" {
+		return nil, nil, false
+	}
+
+	metaPath, bodyPath := httpCachePaths(dir, url)
+
+	metaBytes, err := os.ReadFile(metaPath)
+	if err != nil {
+		return nil, nil, false
+	}
+
+	var e httpCacheEntry
+	if err := json.Unmarshal(metaBytes, &e); err != nil {
+		return nil, nil, false
+	}
+
+	body, err = os.ReadFile(bodyPath)
+	if err != nil {
+		return nil, nil, false
+	}
+
+	return &e, body, true
+}
+
+func storeHTTPCacheEntry(url string, entry httpCacheEntry, body []byte) {
+	dir := httpCacheDir()
+	if dir == "" {
+		return
+	}
+
+	metaPath, bodyPath := httpCachePaths(dir, url)
+	if err := os.MkdirAll(filepath.Dir(metaPath), 0o755); err != nil {
+		return
+	}
+
+	metaBytes, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	_ = os.WriteFile(metaPath, metaBytes, 0o644)
+	_ = os.WriteFile(bodyPath, body, 0o644)
+}
+
+func newHTTPCacheEntry(resp *http.Response) httpCacheEntry {
+	entry := httpCacheEntry{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		FetchedAt:    time.Now(),
+		Header:       resp.Header.Clone(),
+	}
+
+	if maxAge, ok := parseCacheControlMaxAge(resp.Header.Get("Cache-Control")); ok {
+		entry.MaxAge = maxAge
+	}
+
+	return entry
+}
+
+func parseCacheControlMaxAge(header string) (int, bool) {
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		lower := strings.ToLower(part)
+		if !strings.HasPrefix(lower, "max-age=") {
+			continue
+		}
+
+		if n, err := strconv.Atoi(strings.TrimSpace(part[len("max-age="):])); err == nil {
+			return n, true
+		}
+	}
+
+	return 0, false
+}
+
+func withinHTTPCacheStaleGrace(entry *httpCacheEntry) bool {
+	grace := getEnvDuration("GEOIP_HTTP_CACHE_STALE_GRACE", defaultHTTPCacheStaleGrace)
+	return time.Since(entry.FetchedAt) <= grace
+}
+
+// withinHTTPCacheFreshness reports whether entry is still within the
+// max-age the origin advertised when it was stored, letting callers serve
+// it without a conditional GET at all.
+func withinHTTPCacheFreshness(entry *httpCacheEntry) bool {
+	if entry.MaxAge <= 0 {
+		return false
+	}
+
+	return time.Since(entry.FetchedAt) < time.Duration(entry.MaxAge)*time.Second
+}
+
+func newBufferedHTTPResponse(resp *http.Response, body []byte) *http.Response {
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	return resp
+}
+
+func newCachedHTTPResponse(entry *httpCacheEntry, body []byte) *http.Response {
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Status:        "200 OK (cached)",
+		Header:        entry.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+}
@@ -0,0 +1,147 @@
+package lib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func withHTTPCacheDir(t *testing.T) {
+	t.Helper()
+	t.Setenv("GEOIP_HTTP_CACHE_DIR", t.TempDir())
+}
+
+func TestGetRemoteURLContentCachesAndSendsConditionalGET(t *testing.T) {
+	withHTTPCacheDir(t)
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", `"v1"`)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("body-v1"))
+	}))
+	defer server.Close()
+
+	body, err := GetRemoteURLContent(server.URL)
+	if err != nil {
+		t.Fatalf("first fetch failed: %v", err)
+	}
+	if string(body) != "body-v1" {
+		t.Fatalf("got %q, want %q", body, "body-v1")
+	}
+
+	body, err = GetRemoteURLContent(server.URL)
+	if err != nil {
+		t.Fatalf("second fetch failed: %v", err)
+	}
+	if string(body) != "body-v1" {
+		t.Fatalf("cached fetch got %q, want %q", body, "body-v1")
+	}
+	if requests != 2 {
+		t.Fatalf("got %d requests, want 2 (one real fetch, one conditional GET)", requests)
+	}
+}
+
+func TestGetRemoteURLContentSkipsNetworkWhenFresh(t *testing.T) {
+	withHTTPCacheDir(t)
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Cache-Control", "max-age=3600")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("fresh"))
+	}))
+	defer server.Close()
+
+	if _, err := GetRemoteURLContent(server.URL); err != nil {
+		t.Fatalf("first fetch failed: %v", err)
+	}
+	if _, err := GetRemoteURLContent(server.URL); err != nil {
+		t.Fatalf("second fetch failed: %v", err)
+	}
+
+	if requests != 1 {
+		t.Fatalf("got %d requests, want 1 (second call should be served from cache without hitting the network)", requests)
+	}
+}
+
+func TestGetRemoteURLContentFallsBackToStaleCacheOnNetworkFailure(t *testing.T) {
+	withHTTPCacheDir(t)
+	t.Setenv("GEOIP_HTTP_MAX_RETRIES", "1")
+
+	var fail bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			panic(http.ErrAbortHandler)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("stale-ok"))
+	}))
+	defer server.Close()
+
+	if _, err := GetRemoteURLContent(server.URL); err != nil {
+		t.Fatalf("first fetch failed: %v", err)
+	}
+
+	fail = true
+
+	body, err := GetRemoteURLContent(server.URL)
+	if err != nil {
+		t.Fatalf("expected stale cache fallback, got error: %v", err)
+	}
+	if string(body) != "stale-ok" {
+		t.Fatalf("got %q, want stale cached body %q", body, "stale-ok")
+	}
+}
+
+func TestGetRemoteURLContentDoesNotFallBackOnNonRetryableStatus(t *testing.T) {
+	withHTTPCacheDir(t)
+
+	var notFound bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if notFound {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("first"))
+	}))
+	defer server.Close()
+
+	if _, err := GetRemoteURLContent(server.URL); err != nil {
+		t.Fatalf("first fetch failed: %v", err)
+	}
+
+	notFound = true
+
+	if _, err := GetRemoteURLContent(server.URL); err == nil {
+		t.Fatal("expected an error for a 404 response, got a stale cache hit instead")
+	}
+}
+
+func TestWithinHTTPCacheFreshness(t *testing.T) {
+	fresh := &httpCacheEntry{MaxAge: 60, FetchedAt: time.Now()}
+	if !withinHTTPCacheFreshness(fresh) {
+		t.Error("expected a just-fetched entry with max-age=60 to be fresh")
+	}
+
+	expired := &httpCacheEntry{MaxAge: 60, FetchedAt: time.Now().Add(-2 * time.Minute)}
+	if withinHTTPCacheFreshness(expired) {
+		t.Error("expected an entry fetched 2m ago with max-age=60 to be stale")
+	}
+
+	noMaxAge := &httpCacheEntry{FetchedAt: time.Now()}
+	if withinHTTPCacheFreshness(noMaxAge) {
+		t.Error("expected an entry with no max-age to never be considered fresh")
+	}
+}
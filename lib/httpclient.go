@@ -0,0 +1,198 @@
+package lib
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/proxy"
+)
+
+const defaultUserAgent = "geoip/1.0 (+https://github.com/Aethersailor/geoip)"
+
+var (
+	httpClientMu     sync.RWMutex
+	customHTTPClient *http.Client
+
+	defaultHTTPClient    *http.Client
+	defaultHTTPClientKey string
+)
+
+// SetHTTPClient overrides the *http.Client used for all remote fetches. Pass
+// nil to restore the default client built from GEOIP_HTTP_* environment
+// variables.
+func SetHTTPClient(client *http.Client) {
+	httpClientMu.Lock()
+	defer httpClientMu.Unlock()
+	customHTTPClient = client
+}
+
+// getHTTPClient returns the client to use for a fetch. Absent a
+// SetHTTPClient override, it lazily builds one *http.Client (and its
+// pooling *http.Transport) and reuses it across calls so a batch of fetches
+// shares connections instead of opening a fresh pool per request. The
+// shared client has no Timeout of its own - callers get per-request
+// deadlines from the context passed to getRemoteURLResponseContext instead,
+// since mutating a shared client's Timeout field races with concurrent
+// in-flight requests reading it. The cached client is rebuilt only when the
+// environment variables that affect transport construction (proxy, TLS)
+// actually change.
+func getHTTPClient() (*http.Client, error) {
+	httpClientMu.RLock()
+	client := customHTTPClient
+	httpClientMu.RUnlock()
+
+	if client != nil {
+		return client, nil
+	}
+
+	key := defaultHTTPClientEnvKey()
+
+	httpClientMu.Lock()
+	defer httpClientMu.Unlock()
+
+	if defaultHTTPClient == nil || defaultHTTPClientKey != key {
+		transport, err := buildHTTPTransport()
+		if err != nil {
+			return nil, err
+		}
+
+		defaultHTTPClient = &http.Client{Transport: transport}
+		defaultHTTPClientKey = key
+	}
+
+	return defaultHTTPClient, nil
+}
+
+// defaultHTTPClientEnvKey captures the environment variables that
+// buildHTTPTransport reads, so getHTTPClient can detect when the cached
+// default client has gone stale and needs rebuilding.
+func defaultHTTPClientEnvKey() string {
+	return strings.Join([]string{
+		os.Getenv("GEOIP_HTTP_PROXY"),
+		os.Getenv("HTTPS_PROXY"),
+		os.Getenv("GEOIP_HTTP_CA_FILE"),
+		os.Getenv("GEOIP_HTTP_CLIENT_CERT_FILE"),
+		os.Getenv("GEOIP_HTTP_CLIENT_KEY_FILE"),
+		os.Getenv("GEOIP_HTTP_MIN_TLS_VERSION"),
+	}, "\x00")
+}
+
+func httpUserAgent() string {
+	if ua := strings.TrimSpace(os.Getenv("GEOIP_HTTP_USER_AGENT")); ua != "" {
+		return ua
+	}
+
+	return defaultUserAgent
+}
+
+func buildHTTPTransport() (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if err := applyHTTPProxy(transport); err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := buildTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	return transport, nil
+}
+
+func applyHTTPProxy(transport *http.Transport) error {
+	proxyURL := strings.TrimSpace(os.Getenv("GEOIP_HTTP_PROXY"))
+	if proxyURL == "" {
+		proxyURL = strings.TrimSpace(os.Getenv("HTTPS_PROXY"))
+	}
+	if proxyURL == "" {
+		return nil
+	}
+
+	parsed, err := url.Parse(proxyURL)
+	if err != nil {
+		return fmt.Errorf("invalid proxy URL -> %s: %w", proxyURL, err)
+	}
+
+	if parsed.Scheme == "socks5" {
+		dialer, err := proxy.FromURL(parsed, proxy.Direct)
+		if err != nil {
+			return fmt.Errorf("failed to configure SOCKS5 proxy -> %s: %w", proxyURL, err)
+		}
+
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialer.Dial(network, addr)
+		}
+
+		return nil
+	}
+
+	transport.Proxy = http.ProxyURL(parsed)
+	return nil
+}
+
+func buildTLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tlsMinVersion(),
+	}
+
+	if caFile := strings.TrimSpace(os.Getenv("GEOIP_HTTP_CA_FILE")); caFile != "" {
+		pool, err := systemCertPoolWithExtra(caFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	certFile := strings.TrimSpace(os.Getenv("GEOIP_HTTP_CLIENT_CERT_FILE"))
+	keyFile := strings.TrimSpace(os.Getenv("GEOIP_HTTP_CLIENT_KEY_FILE"))
+	if certFile != "" && keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+func systemCertPoolWithExtra(caFile string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file -> %s: %w", caFile, err)
+	}
+
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("failed to parse CA file -> %s", caFile)
+	}
+
+	return pool, nil
+}
+
+func tlsMinVersion() uint16 {
+	switch strings.TrimSpace(os.Getenv("GEOIP_HTTP_MIN_TLS_VERSION")) {
+	case "1.0":
+		return tls.VersionTLS10
+	case "1.1":
+		return tls.VersionTLS11
+	case "1.3":
+		return tls.VersionTLS13
+	default:
+		return tls.VersionTLS12
+	}
+}
@@ -0,0 +1,43 @@
+package lib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// TestGetRemoteURLContentConcurrentSharedClient exercises many concurrent
+// fetches through the cached default client. It exists to catch a data race
+// on the shared *http.Client (run with -race): a prior version mutated
+// client.Timeout on every call, racing with other goroutines' in-flight
+// client.Do.
+func TestGetRemoteURLContentConcurrentSharedClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	SetHTTPClient(nil)
+
+	const concurrency = 20
+
+	var wg sync.WaitGroup
+	errs := make([]error, concurrency)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = GetRemoteURLContent(server.URL)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("fetch %d failed: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package lib
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTLSMinVersion(t *testing.T) {
+	cases := map[string]uint16{
+		"":     tls.VersionTLS12,
+		"1.0":  tls.VersionTLS10,
+		"1.1":  tls.VersionTLS11,
+		"1.3":  tls.VersionTLS13,
+		"junk": tls.VersionTLS12,
+	}
+
+	for value, want := range cases {
+		t.Setenv("GEOIP_HTTP_MIN_TLS_VERSION", value)
+		if got := tlsMinVersion(); got != want {
+			t.Errorf("GEOIP_HTTP_MIN_TLS_VERSION=%q: got %#x, want %#x", value, got, want)
+		}
+	}
+}
+
+func TestApplyHTTPProxyHTTPScheme(t *testing.T) {
+	t.Setenv("GEOIP_HTTP_PROXY", "http://proxy.example.com:8080")
+
+	transport := &http.Transport{}
+	if err := applyHTTPProxy(transport); err != nil {
+		t.Fatalf("applyHTTPProxy failed: %v", err)
+	}
+	if transport.Proxy == nil {
+		t.Fatal("expected transport.Proxy to be set")
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com", nil)
+	proxyURL, err := transport.Proxy(req)
+	if err != nil {
+		t.Fatalf("transport.Proxy returned error: %v", err)
+	}
+	if proxyURL == nil || proxyURL.Host != "proxy.example.com:8080" {
+		t.Fatalf("got proxy %v, want proxy.example.com:8080", proxyURL)
+	}
+}
+
+func TestApplyHTTPProxySocks5Scheme(t *testing.T) {
+	t.Setenv("GEOIP_HTTP_PROXY", "socks5://127.0.0.1:1080")
+
+	transport := &http.Transport{}
+	if err := applyHTTPProxy(transport); err != nil {
+		t.Fatalf("applyHTTPProxy failed: %v", err)
+	}
+	if transport.DialContext == nil {
+		t.Fatal("expected a SOCKS5 DialContext to be set")
+	}
+}
+
+func TestApplyHTTPProxyInvalidURL(t *testing.T) {
+	t.Setenv("GEOIP_HTTP_PROXY", "://not-a-url")
+
+	if err := applyHTTPProxy(&http.Transport{}); err == nil {
+		t.Fatal("expected an error for a malformed proxy URL")
+	}
+}
+
+func TestApplyHTTPProxyFallsBackToHTTPSProxyEnv(t *testing.T) {
+	t.Setenv("GEOIP_HTTP_PROXY", "")
+	t.Setenv("HTTPS_PROXY", "http://fallback.example.com:3128")
+
+	transport := &http.Transport{}
+	if err := applyHTTPProxy(transport); err != nil {
+		t.Fatalf("applyHTTPProxy failed: %v", err)
+	}
+	if transport.Proxy == nil {
+		t.Fatal("expected transport.Proxy to be set from HTTPS_PROXY")
+	}
+}
+
+func TestBuildTLSConfigLoadsClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeSelfSignedCert(t, dir)
+
+	t.Setenv("GEOIP_HTTP_CLIENT_CERT_FILE", certFile)
+	t.Setenv("GEOIP_HTTP_CLIENT_KEY_FILE", keyFile)
+
+	cfg, err := buildTLSConfig()
+	if err != nil {
+		t.Fatalf("buildTLSConfig failed: %v", err)
+	}
+	if len(cfg.Certificates) != 1 {
+		t.Fatalf("got %d certificates, want 1", len(cfg.Certificates))
+	}
+}
+
+func TestBuildTLSConfigLoadsCustomCA(t *testing.T) {
+	dir := t.TempDir()
+	certFile, _ := writeSelfSignedCert(t, dir)
+
+	t.Setenv("GEOIP_HTTP_CA_FILE", certFile)
+
+	cfg, err := buildTLSConfig()
+	if err != nil {
+		t.Fatalf("buildTLSConfig failed: %v", err)
+	}
+	if cfg.RootCAs == nil {
+		t.Fatal("expected RootCAs to be populated")
+	}
+}
+
+func TestSystemCertPoolWithExtraMissingFile(t *testing.T) {
+	if _, err := systemCertPoolWithExtra(filepath.Join(t.TempDir(), "does-not-exist.pem")); err == nil {
+		t.Fatal("expected an error for a missing CA file")
+	}
+}
+
+func TestSystemCertPoolWithExtraMalformedPEM(t *testing.T) {
+	dir := t.TempDir()
+	badFile := filepath.Join(dir, "bad.pem")
+	if err := os.WriteFile(badFile, []byte("not a certificate"), 0o644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	if _, err := systemCertPoolWithExtra(badFile); err == nil {
+		t.Fatal("expected an error for a malformed CA file")
+	}
+}
+
+// writeSelfSignedCert writes a throwaway self-signed certificate and key
+// pair to dir and returns their paths, for exercising the CA/client-cert
+// loading paths without a real PKI.
+func writeSelfSignedCert(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "geoip-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		t.Fatalf("failed to write cert: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o644); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+
+	return certFile, keyFile
+}
@@ -0,0 +1,116 @@
+package lib
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+func TestVerifyArchiveDigestMismatch(t *testing.T) {
+	err := verifyArchive([]byte("actual"), Verifier{SHA256: "0000000000000000000000000000000000000000000000000000000000000000"})
+	if err == nil {
+		t.Fatal("expected a digest mismatch error")
+	}
+}
+
+func TestVerifyArchiveDigestMatch(t *testing.T) {
+	data := []byte("hello, geoip")
+	sum := sha256.Sum256(data)
+	expected := hex.EncodeToString(sum[:])
+
+	if err := verifyArchive(data, Verifier{SHA256: expected}); err != nil {
+		t.Fatalf("expected matching digest to verify, got: %v", err)
+	}
+}
+
+func TestVerifyArchiveNoExpectations(t *testing.T) {
+	if err := verifyArchive([]byte("data"), Verifier{}); err == nil {
+		t.Fatal("expected an error when no checksum or signature is provided")
+	}
+}
+
+func TestVerifyPGPSignature(t *testing.T) {
+	entity, err := openpgp.NewEntity("Test Signer", "", "test@example.com", &packet.Config{})
+	if err != nil {
+		t.Fatalf("failed to generate test key: %v", err)
+	}
+
+	data := []byte("archive contents")
+
+	var sigBuf bytes.Buffer
+	if err := openpgp.DetachSign(&sigBuf, entity, bytes.NewReader(data), &packet.Config{DefaultHash: crypto.SHA256}); err != nil {
+		t.Fatalf("failed to create detached signature: %v", err)
+	}
+
+	var pubBuf bytes.Buffer
+	armorWriter, err := armor.Encode(&pubBuf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatalf("failed to open armor writer: %v", err)
+	}
+	if err := entity.Serialize(armorWriter); err != nil {
+		t.Fatalf("failed to serialize public key: %v", err)
+	}
+	armorWriter.Close()
+
+	if err := verifyPGPSignature(data, sigBuf.Bytes(), pubBuf.String()); err != nil {
+		t.Fatalf("expected valid signature to verify, got: %v", err)
+	}
+
+	if err := verifyPGPSignature([]byte("tampered contents"), sigBuf.Bytes(), pubBuf.String()); err == nil {
+		t.Fatal("expected signature verification to fail for tampered data")
+	}
+}
+
+func TestVerifiedFetchStrictModeRejectsMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("real content"))
+	}))
+	defer server.Close()
+
+	t.Setenv("GEOIP_VERIFY_CHECKSUMS", "strict")
+
+	_, err := VerifiedFetch(server.URL, Verifier{SHA256: "0000000000000000000000000000000000000000000000000000000000000000"})
+	if err == nil {
+		t.Fatal("expected strict mode to return an error on checksum mismatch")
+	}
+}
+
+func TestVerifiedFetchWarnModeReturnsDataOnMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("real content"))
+	}))
+	defer server.Close()
+
+	t.Setenv("GEOIP_VERIFY_CHECKSUMS", "warn")
+
+	data, err := VerifiedFetch(server.URL, Verifier{SHA256: "0000000000000000000000000000000000000000000000000000000000000000"})
+	if err != nil {
+		t.Fatalf("warn mode should not return an error, got: %v", err)
+	}
+	if string(data) != "real content" {
+		t.Fatalf("got %q, want %q", data, "real content")
+	}
+}
+
+func TestFetchSHA256Sidecar(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("deadbeef  GeoLite2-Country.mmdb\n"))
+	}))
+	defer server.Close()
+
+	digest, err := FetchSHA256Sidecar(server.URL)
+	if err != nil {
+		t.Fatalf("FetchSHA256Sidecar failed: %v", err)
+	}
+	if digest != "deadbeef" {
+		t.Fatalf("got %q, want %q", digest, "deadbeef")
+	}
+}